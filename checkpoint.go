@@ -0,0 +1,54 @@
+package mongomove
+
+import "time"
+
+// progressCollection is the name of the collection that mongomove uses on
+// the target instance to record per-namespace import checkpoints when
+// Resume is enabled.
+const progressCollection = "mongomove_progress"
+
+// Progress is a single namespace's checkpoint, as recorded in the target's
+// mongomove_progress collection by a resumable Import.
+type Progress struct {
+	// LastID is the _id of the last document written for the namespace, used
+	// to resume its Find past it instead of starting over.
+	LastID interface{}
+	// DocsCopied is the number of documents written for the namespace so
+	// far.
+	DocsCopied int64
+	// IndexesCreated reports whether ensureColIndexes has already run for
+	// the namespace.
+	IndexesCreated bool
+	// CompletedAt is set once every document of the namespace has been
+	// copied, so a later Import can skip it entirely.
+	CompletedAt *time.Time
+}
+
+// progressDoc is the document shape stored in progressCollection, one per
+// namespace.
+type progressDoc struct {
+	ID             string      `bson:"_id"`
+	LastID         interface{} `bson:"lastId,omitempty"`
+	DocsCopied     int64       `bson:"docsCopied"`
+	IndexesCreated bool        `bson:"indexesCreated"`
+	CompletedAt    *time.Time  `bson:"completedAt,omitempty"`
+}
+
+// Resume sets whether Import checkpoints its progress per namespace in a
+// "mongomove_progress" collection on the target, skipping collections
+// already marked complete and resuming partially copied ones past their
+// last written _id instead of starting over.
+func Resume(resume bool) ImportOption {
+	return func(cfg *importConfig) {
+		cfg.resume = resume
+	}
+}
+
+// Force makes a resumed Import reprocess namespaces that are already marked
+// complete, instead of skipping them. It has no effect unless Resume is
+// also set.
+func Force(force bool) ImportOption {
+	return func(cfg *importConfig) {
+		cfg.force = force
+	}
+}
@@ -27,17 +27,42 @@ func (el *excludeList) Set(val string) error {
 	return nil
 }
 
+func compileAll(exprs []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, len(exprs))
+	for i, ex := range exprs {
+		expr, err := regexp.Compile(ex)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", ex, err)
+		}
+		compiled[i] = expr
+	}
+	return compiled, nil
+}
+
 func main() {
 	var exclude excludeList
+	var includeNS excludeList
+	var excludeNS excludeList
 	source := flag.String("source", "mongodb://127.0.0.1:27017", "Source URI")
 	target := flag.String("target", "mongodb://127.0.0.1:27018", "Target URI")
+	sourceArchive := flag.String("source-archive", "", "Read from a mongomove archive file instead of --source")
+	targetArchive := flag.String("target-archive", "", "Write to a mongomove archive file instead of --target")
 	prefix := flag.String("prefix", "", "Database prefix (filter)")
 	flag.Var(&exclude, "exclude", "Exclude databases (regexp)")
+	flag.Var(&includeNS, "include-ns", "Include namespaces \"db.collection\" (regexp, repeatable)")
+	flag.Var(&excludeNS, "exclude-ns", "Exclude namespaces \"db.collection\" (regexp, repeatable)")
 	drop := flag.Bool("drop", false, "Drop target databases before import")
+	mode := flag.String("mode", "insert", "Write mode: insert, upsert or merge")
+	continueOnError := flag.Bool("continue-on-error", false, "Don't fail an import on duplicate key errors")
+	skipViews := flag.Bool("skip-views", false, "Don't recreate views, copy them like regular collections")
+	skipCapped := flag.Bool("skip-capped", false, "Don't replicate capped/time-series properties on the target")
 	skipConfirm := flag.Bool("confirm", false, "Don't ask for confirmation")
 	parallel := flag.Int("parallel", runtime.NumCPU(), "Control parallelism")
 	batchSize := flag.Int("batch", 100, "Batch inserts")
 	verbose := flag.Bool("verbose", false, "Log debug info")
+	follow := flag.Bool("follow", false, "Keep syncing changes after the initial import (requires a replica set source)")
+	resume := flag.Bool("resume", false, "Checkpoint progress on the target and resume an interrupted import instead of starting over")
+	force := flag.Bool("force", false, "With --resume, reprocess namespaces already marked complete")
 
 	// short flags
 	flag.StringVar(source, "s", "mongodb://127.0.0.1:27017", "Source URI")
@@ -50,12 +75,17 @@ func main() {
 
 	flag.Parse()
 
-	if *source == "" {
+	if *sourceArchive != "" && *targetArchive != "" {
+		fmt.Println("--source-archive and --target-archive cannot be used together.")
+		os.Exit(1)
+	}
+
+	if *sourceArchive == "" && *source == "" {
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	if *target == "" {
+	if *targetArchive == "" && *target == "" {
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
@@ -63,21 +93,53 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
 	defer stop()
 
-	sourcec, err := mongo.Connect(ctx, options.Client().ApplyURI(*source))
-	if err != nil {
-		fmt.Printf("Failed to connect to source: %v\n", err)
-		os.Exit(1)
-	}
-	defer sourcec.Disconnect(context.Background())
+	var i *mongomove.Importer
+	switch {
+	case *sourceArchive != "":
+		targetc, err := mongo.Connect(ctx, options.Client().ApplyURI(*target))
+		if err != nil {
+			fmt.Printf("Failed to connect to target: %v\n", err)
+			os.Exit(1)
+		}
+		defer targetc.Disconnect(context.Background())
 
-	targetc, err := mongo.Connect(ctx, options.Client().ApplyURI(*target))
-	if err != nil {
-		fmt.Printf("Failed to connect to target: %v\n", err)
-		os.Exit(1)
-	}
-	defer targetc.Disconnect(context.Background())
+		i, err = mongomove.NewFromArchive(*sourceArchive, targetc)
+		if err != nil {
+			fmt.Printf("Failed to open source archive: %v\n", err)
+			os.Exit(1)
+		}
+		defer i.Close()
+	case *targetArchive != "":
+		sourcec, err := mongo.Connect(ctx, options.Client().ApplyURI(*source))
+		if err != nil {
+			fmt.Printf("Failed to connect to source: %v\n", err)
+			os.Exit(1)
+		}
+		defer sourcec.Disconnect(context.Background())
+
+		i, err = mongomove.NewToArchive(sourcec, *targetArchive)
+		if err != nil {
+			fmt.Printf("Failed to create target archive: %v\n", err)
+			os.Exit(1)
+		}
+		defer i.Close()
+	default:
+		sourcec, err := mongo.Connect(ctx, options.Client().ApplyURI(*source))
+		if err != nil {
+			fmt.Printf("Failed to connect to source: %v\n", err)
+			os.Exit(1)
+		}
+		defer sourcec.Disconnect(context.Background())
+
+		targetc, err := mongo.Connect(ctx, options.Client().ApplyURI(*target))
+		if err != nil {
+			fmt.Printf("Failed to connect to target: %v\n", err)
+			os.Exit(1)
+		}
+		defer targetc.Disconnect(context.Background())
 
-	i := mongomove.New(sourcec, targetc)
+		i = mongomove.New(sourcec, targetc)
+	}
 
 	var opts []mongomove.ImportOption
 	if *prefix != "" {
@@ -86,6 +148,31 @@ func main() {
 	if *drop {
 		opts = append(opts, mongomove.Drop(true))
 	}
+	if *skipViews {
+		opts = append(opts, mongomove.SkipViews(true))
+	}
+	if *skipCapped {
+		opts = append(opts, mongomove.SkipCapped(true))
+	}
+	switch *mode {
+	case "insert":
+	case "upsert":
+		opts = append(opts, mongomove.Mode(mongomove.ModeUpsert))
+	case "merge":
+		opts = append(opts, mongomove.Mode(mongomove.ModeMerge))
+	default:
+		fmt.Printf("Invalid mode %q, must be one of: insert, upsert, merge\n", *mode)
+		os.Exit(1)
+	}
+	if *continueOnError {
+		opts = append(opts, mongomove.ContinueOnError(true))
+	}
+	if *resume {
+		opts = append(opts, mongomove.Resume(true))
+	}
+	if *force {
+		opts = append(opts, mongomove.Force(true))
+	}
 	if *skipConfirm {
 		opts = append(opts, mongomove.SkipConfirm(true))
 	}
@@ -104,10 +191,31 @@ func main() {
 		}
 		opts = append(opts, mongomove.Exclude(exprs...))
 	}
+	if len(includeNS) > 0 {
+		exprs, err := compileAll(includeNS)
+		if err != nil {
+			fmt.Printf("Failed to compile include-ns filter: %v\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, mongomove.IncludeNamespace(exprs...))
+	}
+	if len(excludeNS) > 0 {
+		exprs, err := compileAll(excludeNS)
+		if err != nil {
+			fmt.Printf("Failed to compile exclude-ns filter: %v\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, mongomove.ExcludeNamespace(exprs...))
+	}
 	opts = append(opts, mongomove.Parallel(*parallel), mongomove.BatchSize(*batchSize))
 
 	start := time.Now()
-	if err := i.Import(ctx, opts...); err != nil {
+	if *follow {
+		if err := i.Sync(ctx, opts...); err != nil {
+			fmt.Printf("Failed to sync: %v\n", err)
+			os.Exit(1)
+		}
+	} else if err := i.Import(ctx, opts...); err != nil {
 		fmt.Printf("Failed to do import: %v\n", err)
 		os.Exit(1)
 	}
@@ -2,6 +2,7 @@ package mongomove
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -21,15 +22,18 @@ const (
 	defaultPingTimeout = 5 * time.Second
 )
 
-// Importer is a type that performs data import operations between two MongoDB
-// instances, handling database filtering, index management, and parallel
-// processing for efficient data transfers. It provides options to customize the
-// import process, such as specifying filters for databases, ensuring indexes
-// are created on the target instance, and configuring batch sizes and parallel
-// processing.
+// Importer is a type that performs data import operations between a Source
+// and a Target, handling database and collection filtering, index
+// management, and parallel processing for efficient data transfers. Both the
+// source and the target can be either a live MongoDB deployment or a
+// mongomove archive file (its own format, not mongodump-compatible — see
+// archive.go). It provides options to customize the
+// import process, such as specifying filters for databases and collections,
+// ensuring indexes are created on the target instance, and configuring batch
+// sizes and parallel processing.
 type Importer struct {
-	source *mongo.Client
-	target *mongo.Client
+	source Source
+	target Target
 }
 
 // ImportOption is a configuration function that modifies the importConfig
@@ -41,14 +45,70 @@ type Importer struct {
 type ImportOption func(*importConfig)
 
 type importConfig struct {
-	dbFilter      []func(string) bool
-	ensureIndexes bool
-	drop          bool
-	skipConfirm   bool
-	verbose       bool
-	pingTimeout   time.Duration
-	parallel      int
-	batchSize     int
+	dbFilter        []func(string) bool
+	colFilter       []func(db, collection string) bool
+	ensureIndexes   bool
+	drop            bool
+	skipConfirm     bool
+	skipViews       bool
+	skipCapped      bool
+	mode            ImportMode
+	continueOnError bool
+	resume          bool
+	force           bool
+	transforms      []TransformFunc
+	aggPipelines    map[string]mongo.Pipeline
+	verbose         bool
+	pingTimeout     time.Duration
+	parallel        int
+	batchSize       int
+}
+
+// ImportMode controls how importCollection writes documents to the target:
+// whether it always inserts, replaces documents on conflict, or merges
+// fields into existing documents.
+type ImportMode int
+
+const (
+	// ModeInsert inserts every document, the same as a fresh import. A
+	// document that already exists on the target (same _id) makes the whole
+	// batch fail unless ContinueOnError is set.
+	ModeInsert ImportMode = iota
+	// ModeUpsert replaces a target document with the same _id, or inserts it
+	// if it doesn't exist yet.
+	ModeUpsert
+	// ModeMerge sets the fields of the source document onto the target
+	// document with the same _id, or inserts it if it doesn't exist yet,
+	// leaving any other target-only fields untouched.
+	ModeMerge
+)
+
+// Mode sets the ImportMode used by importCollection when writing documents
+// to the target.
+func Mode(mode ImportMode) ImportOption {
+	return func(cfg *importConfig) {
+		cfg.mode = mode
+	}
+}
+
+// UpsertOnConflict is a shorthand for Mode(ModeUpsert): if upsert is true,
+// re-running an import replaces existing documents instead of failing on
+// duplicate _ids.
+func UpsertOnConflict(upsert bool) ImportOption {
+	return func(cfg *importConfig) {
+		if upsert {
+			cfg.mode = ModeUpsert
+		}
+	}
+}
+
+// ContinueOnError sets whether a batch write continues past duplicate key
+// errors instead of failing the whole import. It only suppresses duplicate
+// key errors; any other write error still fails the import.
+func ContinueOnError(continueOnError bool) ImportOption {
+	return func(cfg *importConfig) {
+		cfg.continueOnError = continueOnError
+	}
 }
 
 // FilterDatabases returns an ImportOption that appends one or more filter
@@ -60,6 +120,47 @@ func FilterDatabases(filter ...func(string) bool) ImportOption {
 	}
 }
 
+// FilterCollections returns an ImportOption that appends one or more filter
+// functions to the importConfig's colFilter list. These filter functions are
+// used to determine which collections of the selected databases should be
+// imported. A filter receives the database and collection name and returns
+// true if the collection should be imported.
+func FilterCollections(filter ...func(db, collection string) bool) ImportOption {
+	return func(cfg *importConfig) {
+		cfg.colFilter = append(cfg.colFilter, filter...)
+	}
+}
+
+// IncludeNamespace returns an ImportOption that only imports collections whose
+// namespace (in the form "db.collection") matches at least one of the
+// provided regular expressions.
+func IncludeNamespace(exprs ...*regexp.Regexp) ImportOption {
+	return FilterCollections(func(db, collection string) bool {
+		ns := db + "." + collection
+		for _, expr := range exprs {
+			if expr.MatchString(ns) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ExcludeNamespace returns an ImportOption that excludes collections whose
+// namespace (in the form "db.collection") matches at least one of the
+// provided regular expressions.
+func ExcludeNamespace(exprs ...*regexp.Regexp) ImportOption {
+	return FilterCollections(func(db, collection string) bool {
+		ns := db + "." + collection
+		for _, expr := range exprs {
+			if expr.MatchString(ns) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
 // EnsureIndexes sets the ensureIndexes field in the importConfig struct to the
 // provided boolean value. If set to true, the function ensures that indexes are
 // created on the target database during the import process.
@@ -89,6 +190,24 @@ func Exclude(exprs ...*regexp.Regexp) ImportOption {
 	})
 }
 
+// SkipViews sets the skipViews field in the importConfig struct. If true,
+// views are treated like regular collections instead of being recreated on
+// the target via CreateView, restoring the pre-view-aware behavior.
+func SkipViews(skip bool) ImportOption {
+	return func(cfg *importConfig) {
+		cfg.skipViews = skip
+	}
+}
+
+// SkipCapped sets the skipCapped field in the importConfig struct. If true,
+// capped and time-series collections are imported like regular collections
+// instead of having their properties replicated on the target.
+func SkipCapped(skip bool) ImportOption {
+	return func(cfg *importConfig) {
+		cfg.skipCapped = skip
+	}
+}
+
 // Drop sets the drop option for the importConfig. If true, the target database
 // will be dropped before importing data.
 func Drop(drop bool) ImportOption {
@@ -149,6 +268,10 @@ func New(source, target *mongo.Client) *Importer {
 	if target == nil {
 		panic("<nil> client (target)")
 	}
+	return newImporter(mongoSource{client: source}, mongoTarget{client: target})
+}
+
+func newImporter(source Source, target Target) *Importer {
 	return &Importer{
 		source: source,
 		target: target,
@@ -159,6 +282,11 @@ func New(source, target *mongo.Client) *Importer {
 // target MongoDB client using the provided import options. It handles database
 // filtering, dropping existing databases, ensuring indexes, and parallelizing
 // import operations.
+//
+// With Resume set, Import checkpoints its progress per namespace in a
+// "mongomove_progress" collection on the target, so a restarted Import skips
+// namespaces already marked complete and resumes partially copied ones past
+// their last written _id, instead of starting over.
 func (i *Importer) Import(ctx context.Context, opts ...ImportOption) error {
 	cfg := importConfig{
 		pingTimeout:   defaultPingTimeout,
@@ -178,7 +306,7 @@ func (i *Importer) Import(ctx context.Context, opts ...ImportOption) error {
 		return fmt.Errorf("ping: %w", err)
 	}
 
-	names, err := i.source.ListDatabaseNames(ctx, bson.M{})
+	names, err := i.source.ListDatabases(ctx)
 	if err != nil {
 		return fmt.Errorf("list database names: %w", err)
 	}
@@ -204,15 +332,14 @@ func (i *Importer) Import(ctx context.Context, opts ...ImportOption) error {
 		go func() {
 			defer wg.Done()
 			for name := range jobs {
-				db := i.source.Database(name)
-				if err := cfg.dropDB(ctx, i.target.Database(name)); err != nil {
+				if err := i.dropDB(ctx, cfg, name); err != nil {
 					select {
 					case <-ctx.Done():
 						return
 					case errors <- fmt.Errorf("drop %q database: %w", name, err):
 					}
 				}
-				if err := i.importDatabase(ctx, cfg, db); err != nil {
+				if err := i.importDatabase(ctx, cfg, name); err != nil {
 					select {
 					case <-ctx.Done():
 						return
@@ -254,25 +381,63 @@ func (i *Importer) ping(ctx context.Context, timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	if err := i.source.Ping(ctx, nil); err != nil {
+	if err := i.source.Ping(ctx); err != nil {
 		return fmt.Errorf("ping source: %w", err)
 	}
 
-	if err := i.target.Ping(ctx, nil); err != nil {
+	if err := i.target.Ping(ctx); err != nil {
 		return fmt.Errorf("ping target: %w", err)
 	}
 
 	return nil
 }
 
-func (i *Importer) importDatabase(ctx context.Context, cfg importConfig, db *mongo.Database) error {
-	cfg.log(fmt.Sprintf("Import database: %v", db.Name()))
+// collectionOptions mirrors the subset of the "options" document returned by
+// listCollections that mongomove needs to replicate capped, time-series and
+// view properties on the target.
+type collectionOptions struct {
+	ViewOn     string              `bson:"viewOn,omitempty"`
+	Pipeline   []bson.M            `bson:"pipeline,omitempty"`
+	Capped     bool                `bson:"capped,omitempty"`
+	Size       int64               `bson:"size,omitempty"`
+	Max        int64               `bson:"max,omitempty"`
+	TimeSeries *timeSeriesSpecOpts `bson:"timeseries,omitempty"`
+}
+
+type timeSeriesSpecOpts struct {
+	TimeField   string `bson:"timeField,omitempty"`
+	MetaField   string `bson:"metaField,omitempty"`
+	Granularity string `bson:"granularity,omitempty"`
+}
 
-	names, err := db.ListCollectionNames(ctx, bson.M{})
+func (i *Importer) importDatabase(ctx context.Context, cfg importConfig, dbName string) error {
+	cfg.log(fmt.Sprintf("Import database: %v", dbName))
+
+	specs, err := i.source.ListCollections(ctx, dbName)
 	if err != nil {
-		return fmt.Errorf("list collection names: %w", err)
+		return fmt.Errorf("list collection specifications: %w", err)
+	}
+
+	names := make([]string, len(specs))
+	for i, spec := range specs {
+		names[i] = spec.Name
+	}
+	cfg.log(fmt.Sprintf("[%s]: Found collections: %v", dbName, names))
+
+	names = cfg.filterCollections(dbName, names...)
+	cfg.log(fmt.Sprintf("[%s]: Filtered collections: %v", dbName, names))
+
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		keep[name] = true
+	}
+	filtered := specs[:0]
+	for _, spec := range specs {
+		if keep[spec.Name] {
+			filtered = append(filtered, spec)
+		}
 	}
-	cfg.log(fmt.Sprintf("[%s]: Found collections: %v", db.Name(), names))
+	specs = filtered
 
 	importCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -281,11 +446,11 @@ func (i *Importer) importDatabase(ctx context.Context, cfg importConfig, db *mon
 	defer cancel()
 
 	group, ctx := errgroup.WithContext(importCtx)
-	for _, name := range names {
-		name := name
+	for _, spec := range specs {
+		spec := spec
 		group.Go(func() error {
-			if err := i.importCollection(ctx, cfg, db.Collection(name)); err != nil {
-				return fmt.Errorf("import %q collection: %w", name, err)
+			if err := i.importNamespace(ctx, cfg, dbName, spec); err != nil {
+				return fmt.Errorf("import %q collection: %w", spec.Name, err)
 			}
 			return nil
 		})
@@ -296,7 +461,16 @@ func (i *Importer) importDatabase(ctx context.Context, cfg importConfig, db *mon
 	}
 
 	if cfg.ensureIndexes {
-		if err := i.ensureIndexes(indexCtx, cfg, db, names); err != nil {
+		// Views have no indexes of their own; listIndexes/createIndexes
+		// against a view namespace errors, so they're excluded here even
+		// though they're still part of names/specs for the copy step above.
+		indexNames := make([]string, 0, len(specs))
+		for _, spec := range specs {
+			if spec.Type != "view" {
+				indexNames = append(indexNames, spec.Name)
+			}
+		}
+		if err := i.ensureIndexes(indexCtx, cfg, dbName, indexNames); err != nil {
 			return fmt.Errorf("ensure indexes: %w", err)
 		}
 	} else {
@@ -306,14 +480,153 @@ func (i *Importer) importDatabase(ctx context.Context, cfg importConfig, db *mon
 	return nil
 }
 
-func (i *Importer) importCollection(ctx context.Context, cfg importConfig, col *mongo.Collection) error {
-	cfg.log(fmt.Sprintf("[%s]: Import collection: %v", col.Database().Name(), col.Name()))
+// importNamespace imports a single collection described by spec, dispatching
+// to view recreation or capped/time-series pre-creation depending on its
+// type and options before copying its documents (if any).
+func (i *Importer) importNamespace(ctx context.Context, cfg importConfig, dbName string, spec CollectionSpec) error {
+	var opts collectionOptions
+	if len(spec.Options) > 0 {
+		if err := bson.Unmarshal(spec.Options, &opts); err != nil {
+			return fmt.Errorf("unmarshal collection options: %w", err)
+		}
+	}
 
-	target := i.target.Database(col.Database().Name()).Collection(col.Name())
+	if spec.Type == "view" && !cfg.skipViews {
+		return i.createView(ctx, cfg, dbName, spec, opts)
+	}
 
-	cur, err := col.Find(ctx, bson.M{}, options.Find().SetNoCursorTimeout(true))
-	if err != nil {
-		return fmt.Errorf("find all documents: %w", err)
+	if !cfg.skipCapped {
+		if spec.Type == "timeseries" {
+			if err := i.precreateTimeseries(ctx, cfg, dbName, spec, opts); err != nil {
+				return err
+			}
+		} else if opts.Capped {
+			if err := i.precreateCapped(ctx, cfg, dbName, spec, opts); err != nil {
+				return err
+			}
+		}
+	}
+
+	return i.importCollection(ctx, cfg, dbName, spec.Name)
+}
+
+// createView recreates a view on the target using the source view's "viewOn"
+// collection and aggregation pipeline. Views have no documents of their own,
+// so no data is copied.
+func (i *Importer) createView(ctx context.Context, cfg importConfig, dbName string, spec CollectionSpec, opts collectionOptions) error {
+	cfg.log(fmt.Sprintf("[%s]: Creating view: %v", dbName, spec.Name))
+
+	pipeline := make(mongo.Pipeline, len(opts.Pipeline))
+	for i, stage := range opts.Pipeline {
+		d := make(bson.D, 0, len(stage))
+		for k, v := range stage {
+			d = append(d, bson.E{Key: k, Value: v})
+		}
+		pipeline[i] = d
+	}
+
+	if err := i.target.CreateView(ctx, dbName, spec.Name, opts.ViewOn, pipeline); err != nil {
+		if isNamespaceExistsErr(err) {
+			cfg.log(fmt.Sprintf("[%s]: View %v already exists, leaving it as-is.", dbName, spec.Name))
+			return nil
+		}
+		return fmt.Errorf("create view: %w", err)
+	}
+
+	return nil
+}
+
+// precreateCapped creates the target collection as a capped collection with
+// the same size and document limits as the source, before any documents are
+// copied.
+func (i *Importer) precreateCapped(ctx context.Context, cfg importConfig, dbName string, spec CollectionSpec, opts collectionOptions) error {
+	cfg.log(fmt.Sprintf("[%s]: Pre-creating capped collection: %v", dbName, spec.Name))
+
+	createOpts := options.CreateCollection().SetCapped(true).SetSizeInBytes(opts.Size)
+	if opts.Max > 0 {
+		createOpts.SetMaxDocuments(opts.Max)
+	}
+
+	if err := i.target.CreateCollection(ctx, dbName, spec.Name, createOpts); err != nil {
+		if isNamespaceExistsErr(err) {
+			cfg.log(fmt.Sprintf("[%s]: Capped collection %v already exists, leaving it as-is.", dbName, spec.Name))
+			return nil
+		}
+		return fmt.Errorf("create capped collection: %w", err)
+	}
+
+	return nil
+}
+
+// precreateTimeseries creates the target collection with the same
+// time-series options as the source before any documents are copied.
+func (i *Importer) precreateTimeseries(ctx context.Context, cfg importConfig, dbName string, spec CollectionSpec, opts collectionOptions) error {
+	cfg.log(fmt.Sprintf("[%s]: Pre-creating time-series collection: %v", dbName, spec.Name))
+
+	if opts.TimeSeries == nil {
+		return fmt.Errorf("collection %q has type %q but no timeseries options", spec.Name, spec.Type)
+	}
+
+	tsOpts := options.TimeSeries().SetTimeField(opts.TimeSeries.TimeField)
+	if opts.TimeSeries.MetaField != "" {
+		tsOpts.SetMetaField(opts.TimeSeries.MetaField)
+	}
+	if opts.TimeSeries.Granularity != "" {
+		tsOpts.SetGranularity(opts.TimeSeries.Granularity)
+	}
+
+	createOpts := options.CreateCollection().SetTimeSeriesOptions(tsOpts)
+
+	if err := i.target.CreateCollection(ctx, dbName, spec.Name, createOpts); err != nil {
+		if isNamespaceExistsErr(err) {
+			cfg.log(fmt.Sprintf("[%s]: Time-series collection %v already exists, leaving it as-is.", dbName, spec.Name))
+			return nil
+		}
+		return fmt.Errorf("create time-series collection: %w", err)
+	}
+
+	return nil
+}
+
+func (i *Importer) importCollection(ctx context.Context, cfg importConfig, dbName, collName string) error {
+	var progress *Progress
+	if cfg.resume {
+		var err error
+		progress, err = i.target.LoadProgress(ctx, dbName, collName)
+		if err != nil {
+			return fmt.Errorf("load progress: %w", err)
+		}
+		if progress != nil && progress.CompletedAt != nil && !cfg.force {
+			cfg.log(fmt.Sprintf("[%s/%s]: Already complete, skipping (resume).", dbName, collName))
+			return nil
+		}
+	}
+
+	var resumeAfter interface{}
+	var docsCopied int64
+	var indexesCreated bool
+	if progress != nil {
+		resumeAfter = progress.LastID
+		docsCopied = progress.DocsCopied
+		indexesCreated = progress.IndexesCreated
+	}
+	lastID := resumeAfter
+
+	cfg.log(fmt.Sprintf("[%s]: Import collection: %v", dbName, collName))
+
+	var cur DocumentCursor
+	var err error
+	if pipeline, ok := cfg.aggPipelines[dbName+"."+collName]; ok {
+		cfg.log(fmt.Sprintf("[%s/%s]: Reading through aggregation pipeline.", dbName, collName))
+		cur, err = i.source.Aggregate(ctx, dbName, collName, pipeline)
+		if err != nil {
+			return fmt.Errorf("aggregate documents: %w", err)
+		}
+	} else {
+		cur, err = i.source.Find(ctx, dbName, collName, resumeAfter)
+		if err != nil {
+			return fmt.Errorf("find all documents: %w", err)
+		}
 	}
 	defer cur.Close(ctx)
 
@@ -330,11 +643,18 @@ func (i *Importer) importCollection(ctx context.Context, cfg importConfig, col *
 			qty = l
 		}
 		end := start + qty - 1
-		cfg.log(fmt.Sprintf("[%s/%s]: Inserting documents (%d - %d)...", col.Database().Name(), col.Name(), start, end))
-		if _, err := target.InsertMany(ctx, buf); err != nil {
-			return fmt.Errorf("insert documents: %w", err)
+		cfg.log(fmt.Sprintf("[%s/%s]: Writing documents (%d - %d)...", dbName, collName, start, end))
+		if err := i.writeBatch(ctx, cfg, dbName, collName, buf); err != nil {
+			return err
 		}
-		cfg.log(fmt.Sprintf("[%s/%s]: Inserted documents (%d - %d).", col.Database().Name(), col.Name(), start, end))
+		docsCopied += int64(qty)
+		if cfg.resume {
+			p := Progress{LastID: lastID, DocsCopied: docsCopied, IndexesCreated: indexesCreated}
+			if err := i.target.SaveProgress(ctx, dbName, collName, p); err != nil {
+				return fmt.Errorf("save progress: %w", err)
+			}
+		}
+		cfg.log(fmt.Sprintf("[%s/%s]: Wrote documents (%d - %d).", dbName, collName, start, end))
 		return nil
 	}
 
@@ -343,6 +663,23 @@ func (i *Importer) importCollection(ctx context.Context, cfg importConfig, col *
 		if err := cur.Decode(&doc); err != nil {
 			return fmt.Errorf("decode document: %w", err)
 		}
+		lastID = doc["_id"]
+
+		keep := true
+		for _, fn := range cfg.transforms {
+			var err error
+			doc, keep, err = fn(ctx, dbName, collName, doc)
+			if err != nil {
+				return fmt.Errorf("transform document: %w", err)
+			}
+			if !keep {
+				break
+			}
+		}
+		if !keep {
+			continue
+		}
+
 		buf = append(buf, doc)
 		if len(buf) >= cfg.batchSize {
 			if err := insertBatch(); err != nil {
@@ -360,41 +697,128 @@ func (i *Importer) importCollection(ctx context.Context, cfg importConfig, col *
 		return err
 	}
 
-	cfg.log(fmt.Sprintf("[%s/%s]: Import done.", col.Database().Name(), col.Name()))
+	if cfg.resume {
+		now := time.Now()
+		p := Progress{LastID: lastID, DocsCopied: docsCopied, IndexesCreated: indexesCreated, CompletedAt: &now}
+		if err := i.target.SaveProgress(ctx, dbName, collName, p); err != nil {
+			return fmt.Errorf("save progress: %w", err)
+		}
+	}
+
+	cfg.log(fmt.Sprintf("[%s/%s]: Import done.", dbName, collName))
 
 	return nil
 }
 
-func (i *Importer) ensureIndexes(ctx context.Context, cfg importConfig, db *mongo.Database, names []string) error {
-	cfg.log(fmt.Sprintf("[%s]: Ensure indexes: %v", db.Name(), names))
+// duplicateKeyCode is the MongoDB error code for a duplicate _id (E11000),
+// used by ContinueOnError to tell a harmless re-run conflict apart from a
+// real write failure.
+const duplicateKeyCode = 11000
+
+// namespaceExistsCode is the MongoDB error code returned by create/createView
+// when the target namespace already exists.
+const namespaceExistsCode = 48
+
+// isNamespaceExistsErr reports whether err is a MongoDB "NamespaceExists"
+// command error, used by createView/precreateCapped/precreateTimeseries to
+// tolerate re-running against a namespace a previous (possibly interrupted)
+// Import or Sync call already created.
+func isNamespaceExistsErr(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Code == namespaceExistsCode
+	}
+	return false
+}
+
+// writeBatch writes a batch of documents to the target. With ModeInsert and
+// ContinueOnError both unset (the common case), it uses a plain InsertMany;
+// otherwise it uses an unordered BulkWrite of insert/replace/update-with-
+// upsert models, so ContinueOnError's duplicate-key tolerance also covers
+// re-running a plain import without --drop.
+func (i *Importer) writeBatch(ctx context.Context, cfg importConfig, dbName, collName string, docs []interface{}) error {
+	if cfg.mode == ModeInsert && !cfg.continueOnError {
+		if err := i.target.InsertMany(ctx, dbName, collName, docs); err != nil {
+			return fmt.Errorf("insert documents: %w", err)
+		}
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, len(docs))
+	for idx, v := range docs {
+		doc := v.(bson.M)
+		switch cfg.mode {
+		case ModeUpsert:
+			filter := bson.M{"_id": doc["_id"]}
+			models[idx] = mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(doc).SetUpsert(true)
+		case ModeMerge:
+			filter := bson.M{"_id": doc["_id"]}
+			models[idx] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.M{"$set": doc}).SetUpsert(true)
+		default:
+			models[idx] = mongo.NewInsertOneModel().SetDocument(doc)
+		}
+	}
+
+	err := i.target.BulkWrite(ctx, dbName, collName, models, false)
+	if err == nil {
+		return nil
+	}
+
+	var bwErr mongo.BulkWriteException
+	if cfg.continueOnError && errors.As(err, &bwErr) && onlyDuplicateKeyErrors(bwErr) {
+		return nil
+	}
+
+	return fmt.Errorf("bulk write documents: %w", err)
+}
+
+// onlyDuplicateKeyErrors reports whether every error in a BulkWriteException
+// is a duplicate key error, i.e. the batch only failed because some of its
+// documents already existed on the target.
+func onlyDuplicateKeyErrors(err mongo.BulkWriteException) bool {
+	if err.WriteConcernError != nil {
+		return false
+	}
+	for _, writeErr := range err.WriteErrors {
+		if writeErr.Code != duplicateKeyCode {
+			return false
+		}
+	}
+	return true
+}
+
+func (i *Importer) ensureIndexes(ctx context.Context, cfg importConfig, dbName string, names []string) error {
+	cfg.log(fmt.Sprintf("[%s]: Ensure indexes: %v", dbName, names))
 	for _, name := range names {
-		if err := i.ensureColIndexes(ctx, cfg, db.Collection(name)); err != nil {
+		if err := i.ensureColIndexes(ctx, cfg, dbName, name); err != nil {
 			return fmt.Errorf("ensure indexes for %q collection: %w", name, err)
 		}
 	}
 	return nil
 }
 
-func (i *Importer) ensureColIndexes(ctx context.Context, cfg importConfig, col *mongo.Collection) error {
-	cfg.log(fmt.Sprintf("[%s/%s]: Ensure Collection indexes...", col.Database().Name(), col.Name()))
+func (i *Importer) ensureColIndexes(ctx context.Context, cfg importConfig, dbName, collName string) error {
+	var progress *Progress
+	if cfg.resume {
+		var err error
+		progress, err = i.target.LoadProgress(ctx, dbName, collName)
+		if err != nil {
+			return fmt.Errorf("load progress: %w", err)
+		}
+		if progress != nil && progress.IndexesCreated && !cfg.force {
+			cfg.log(fmt.Sprintf("[%s/%s]: Indexes already created, skipping (resume).", dbName, collName))
+			return nil
+		}
+	}
 
-	target := i.target.Database(col.Database().Name()).Collection(col.Name())
+	cfg.log(fmt.Sprintf("[%s/%s]: Ensure Collection indexes...", dbName, collName))
 
-	cur, err := col.Indexes().List(ctx)
+	models, err := i.source.ListIndexes(ctx, dbName, collName)
 	if err != nil {
 		return fmt.Errorf("list indexes: %w", err)
 	}
 
-	var models []bson.M
-	if err := cur.All(ctx, &models); err != nil {
-		return fmt.Errorf("cursor: %w", err)
-	}
-
-	cfg.log(fmt.Sprintf("[%s/%s]: Found indexes: %v", col.Database().Name(), col.Name(), models))
-
-	if err := cur.Err(); err != nil {
-		return fmt.Errorf("cursor: %w", err)
-	}
+	cfg.log(fmt.Sprintf("[%s/%s]: Found indexes: %v", dbName, collName, models))
 
 	idxModels := make([]mongo.IndexModel, 0, len(models))
 	for i := range models {
@@ -422,13 +846,23 @@ func (i *Importer) ensureColIndexes(ctx context.Context, cfg importConfig, col *
 		})
 	}
 
-	if len(idxModels) > 0 {
-		if _, err := target.Indexes().CreateMany(ctx, idxModels); err != nil {
-			return fmt.Errorf("create indexes: %w", err)
+	if err := i.target.CreateIndexes(ctx, dbName, collName, idxModels); err != nil {
+		return fmt.Errorf("create indexes: %w", err)
+	}
+
+	if cfg.resume {
+		p := Progress{IndexesCreated: true}
+		if progress != nil {
+			p.LastID = progress.LastID
+			p.DocsCopied = progress.DocsCopied
+			p.CompletedAt = progress.CompletedAt
+		}
+		if err := i.target.SaveProgress(ctx, dbName, collName, p); err != nil {
+			return fmt.Errorf("save progress: %w", err)
 		}
 	}
 
-	cfg.log(fmt.Sprintf("[%s/%s]: Indexes created.", col.Database().Name(), col.Name()))
+	cfg.log(fmt.Sprintf("[%s/%s]: Indexes created.", dbName, collName))
 
 	return nil
 }
@@ -451,14 +885,32 @@ L:
 	return filtered
 }
 
-func (cfg importConfig) dropDB(ctx context.Context, db *mongo.Database) error {
+func (cfg importConfig) filterCollections(db string, names ...string) []string {
+	if len(cfg.colFilter) == 0 {
+		return names
+	}
+	var filtered []string
+L:
+	for _, name := range names {
+		for _, filter := range cfg.colFilter {
+			if !filter(db, name) {
+				cfg.log(fmt.Sprintf("Collection %q excluded from import.", db+"."+name))
+				continue L
+			}
+		}
+		filtered = append(filtered, name)
+	}
+	return filtered
+}
+
+func (i *Importer) dropDB(ctx context.Context, cfg importConfig, name string) error {
 	if !cfg.drop {
 		return nil
 	}
 
-	cfg.log(fmt.Sprintf("Dropping target database: %v", db.Name()))
+	cfg.log(fmt.Sprintf("Dropping target database: %v", name))
 
-	return db.Drop(ctx)
+	return i.target.DropDatabase(ctx, name)
 }
 
 func (cfg importConfig) confirm() (bool, error) {
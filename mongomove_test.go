@@ -0,0 +1,64 @@
+package mongomove
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestIsNamespaceExistsErr(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"namespace exists", mongo.CommandError{Code: namespaceExistsCode}, true},
+		{"other command error", mongo.CommandError{Code: 1}, false},
+		{"unrelated error", errors.New("boom"), false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isNamespaceExistsErr(tc.err); got != tc.want {
+				t.Fatalf("isNamespaceExistsErr() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOnlyDuplicateKeyErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		err  mongo.BulkWriteException
+		want bool
+	}{
+		{
+			name: "all duplicate key",
+			err: mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{
+				{WriteError: mongo.WriteError{Code: duplicateKeyCode}},
+				{WriteError: mongo.WriteError{Code: duplicateKeyCode}},
+			}},
+			want: true,
+		},
+		{
+			name: "mixed with other error",
+			err: mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{
+				{WriteError: mongo.WriteError{Code: duplicateKeyCode}},
+				{WriteError: mongo.WriteError{Code: 9999}},
+			}},
+			want: false,
+		},
+		{
+			name: "no write errors",
+			err:  mongo.BulkWriteException{},
+			want: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := onlyDuplicateKeyErrors(tc.err); got != tc.want {
+				t.Fatalf("onlyDuplicateKeyErrors() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package mongomove
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CollectionSpec describes a single collection or view as returned by a
+// Source, carrying just enough information for an Importer to recreate it on
+// a Target (its listCollections "type" and "options" document).
+type CollectionSpec struct {
+	Name    string
+	Type    string
+	Options bson.Raw
+}
+
+// DocumentCursor iterates over the documents of a single collection,
+// regardless of whether they come from a live MongoDB cursor or an archive
+// file.
+type DocumentCursor interface {
+	Next(ctx context.Context) bool
+	Decode(v interface{}) error
+	Err() error
+	Close(ctx context.Context) error
+}
+
+// Source is where an Importer reads data from: a live MongoDB deployment or
+// a mongomove archive file (see archive.go; not mongodump-compatible).
+type Source interface {
+	Ping(ctx context.Context) error
+	ListDatabases(ctx context.Context) ([]string, error)
+	ListCollections(ctx context.Context, db string) ([]CollectionSpec, error)
+	ListIndexes(ctx context.Context, db, collection string) ([]bson.M, error)
+	// Find returns a cursor over the documents of a collection, sorted by
+	// _id ascending. If resumeAfter is non-nil, only documents with an _id
+	// greater than it are returned, letting importCollection resume a
+	// partially copied collection.
+	Find(ctx context.Context, db, collection string, resumeAfter interface{}) (DocumentCursor, error)
+	// Aggregate returns a cursor over the results of an aggregation
+	// pipeline run against a collection, used in place of Find for
+	// namespaces configured with AggregationPipeline.
+	Aggregate(ctx context.Context, db, collection string, pipeline mongo.Pipeline) (DocumentCursor, error)
+}
+
+// Target is where an Importer writes data to: a live MongoDB deployment or a
+// mongomove archive file (see archive.go; not mongodump-compatible).
+type Target interface {
+	Ping(ctx context.Context) error
+	DropDatabase(ctx context.Context, db string) error
+	CreateView(ctx context.Context, db, name, viewOn string, pipeline mongo.Pipeline) error
+	CreateCollection(ctx context.Context, db, name string, opts *options.CreateCollectionOptions) error
+	InsertMany(ctx context.Context, db, collection string, docs []interface{}) error
+	BulkWrite(ctx context.Context, db, collection string, models []mongo.WriteModel, ordered bool) error
+	CreateIndexes(ctx context.Context, db, collection string, models []mongo.IndexModel) error
+	// LoadProgress returns the checkpoint previously saved for a namespace by
+	// SaveProgress, or nil if none was saved yet.
+	LoadProgress(ctx context.Context, db, collection string) (*Progress, error)
+	// SaveProgress persists a namespace's checkpoint, so a restarted Import
+	// can resume instead of starting over.
+	SaveProgress(ctx context.Context, db, collection string, p Progress) error
+}
+
+// mongoSource adapts a live *mongo.Client to the Source interface.
+type mongoSource struct {
+	client *mongo.Client
+}
+
+func (s mongoSource) Client() *mongo.Client { return s.client }
+
+func (s mongoSource) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx, nil)
+}
+
+func (s mongoSource) ListDatabases(ctx context.Context) ([]string, error) {
+	return s.client.ListDatabaseNames(ctx, bson.M{})
+}
+
+func (s mongoSource) ListCollections(ctx context.Context, db string) ([]CollectionSpec, error) {
+	specs, err := s.client.Database(db).ListCollectionSpecifications(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]CollectionSpec, len(specs))
+	for i, spec := range specs {
+		out[i] = CollectionSpec{Name: spec.Name, Type: spec.Type, Options: spec.Options}
+	}
+	return out, nil
+}
+
+func (s mongoSource) ListIndexes(ctx context.Context, db, collection string) ([]bson.M, error) {
+	cur, err := s.client.Database(db).Collection(collection).Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var models []bson.M
+	if err := cur.All(ctx, &models); err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+func (s mongoSource) Find(ctx context.Context, db, collection string, resumeAfter interface{}) (DocumentCursor, error) {
+	filter := bson.M{}
+	if resumeAfter != nil {
+		filter = bson.M{"_id": bson.M{"$gt": resumeAfter}}
+	}
+	findOpts := options.Find().SetNoCursorTimeout(true).SetSort(bson.D{{Key: "_id", Value: 1}})
+	return s.client.Database(db).Collection(collection).Find(ctx, filter, findOpts)
+}
+
+func (s mongoSource) Aggregate(ctx context.Context, db, collection string, pipeline mongo.Pipeline) (DocumentCursor, error) {
+	return s.client.Database(db).Collection(collection).Aggregate(ctx, pipeline)
+}
+
+// mongoTarget adapts a live *mongo.Client to the Target interface.
+type mongoTarget struct {
+	client *mongo.Client
+}
+
+func (t mongoTarget) Client() *mongo.Client { return t.client }
+
+func (t mongoTarget) Ping(ctx context.Context) error {
+	return t.client.Ping(ctx, nil)
+}
+
+func (t mongoTarget) DropDatabase(ctx context.Context, db string) error {
+	return t.client.Database(db).Drop(ctx)
+}
+
+func (t mongoTarget) CreateView(ctx context.Context, db, name, viewOn string, pipeline mongo.Pipeline) error {
+	return t.client.Database(db).CreateView(ctx, name, viewOn, pipeline)
+}
+
+func (t mongoTarget) CreateCollection(ctx context.Context, db, name string, opts *options.CreateCollectionOptions) error {
+	return t.client.Database(db).CreateCollection(ctx, name, opts)
+}
+
+func (t mongoTarget) InsertMany(ctx context.Context, db, collection string, docs []interface{}) error {
+	_, err := t.client.Database(db).Collection(collection).InsertMany(ctx, docs)
+	return err
+}
+
+func (t mongoTarget) BulkWrite(ctx context.Context, db, collection string, models []mongo.WriteModel, ordered bool) error {
+	if len(models) == 0 {
+		return nil
+	}
+	_, err := t.client.Database(db).Collection(collection).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(ordered))
+	return err
+}
+
+func (t mongoTarget) CreateIndexes(ctx context.Context, db, collection string, models []mongo.IndexModel) error {
+	if len(models) == 0 {
+		return nil
+	}
+	_, err := t.client.Database(db).Collection(collection).Indexes().CreateMany(ctx, models)
+	return err
+}
+
+func (t mongoTarget) LoadProgress(ctx context.Context, db, collection string) (*Progress, error) {
+	var doc progressDoc
+	err := t.client.Database(db).Collection(progressCollection).FindOne(ctx, bson.M{"_id": collection}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Progress{
+		LastID:         doc.LastID,
+		DocsCopied:     doc.DocsCopied,
+		IndexesCreated: doc.IndexesCreated,
+		CompletedAt:    doc.CompletedAt,
+	}, nil
+}
+
+func (t mongoTarget) SaveProgress(ctx context.Context, db, collection string, p Progress) error {
+	doc := progressDoc{
+		ID:             collection,
+		LastID:         p.LastID,
+		DocsCopied:     p.DocsCopied,
+		IndexesCreated: p.IndexesCreated,
+		CompletedAt:    p.CompletedAt,
+	}
+	opts := options.Replace().SetUpsert(true)
+	_, err := t.client.Database(db).Collection(progressCollection).ReplaceOne(ctx, bson.M{"_id": collection}, doc, opts)
+	return err
+}
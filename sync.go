@@ -0,0 +1,298 @@
+package mongomove
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/sync/errgroup"
+)
+
+// stateCollection is the name of the collection that mongomove uses on the
+// target instance to persist change stream resume tokens across restarts.
+const stateCollection = "mongomove_state"
+
+// syncState is the document shape stored in stateCollection, one per
+// database being synced.
+type syncState struct {
+	ID          string    `bson:"_id"`
+	ResumeToken bson.Raw  `bson:"resumeToken"`
+	UpdatedAt   time.Time `bson:"updatedAt"`
+}
+
+// Sync performs the same initial import as Import and then keeps the target
+// up to date by tailing the source's change streams, applying insert,
+// update, replace, delete, drop and rename events as they happen. The
+// source must be a replica set, since change streams require an oplog.
+//
+// Sync records a resume token per database in a "mongomove_state" collection
+// on the target, so a restarted Sync call resumes each database's change
+// stream from where it left off. The initial import runs again on every
+// Sync call, whether or not a resume token was persisted.
+func (i *Importer) Sync(ctx context.Context, opts ...ImportOption) error {
+	cfg := importConfig{
+		pingTimeout:   defaultPingTimeout,
+		ensureIndexes: true,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.parallel < 1 {
+		cfg.parallel = 1
+	}
+	if cfg.batchSize < 1 {
+		cfg.batchSize = 1
+	}
+
+	if err := i.ping(ctx, cfg.pingTimeout); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+
+	sourceClient, err := i.liveSourceClient()
+	if err != nil {
+		return err
+	}
+	targetClient, err := i.liveTargetClient()
+	if err != nil {
+		return err
+	}
+
+	isReplSet, err := isReplicaSet(ctx, sourceClient)
+	if err != nil {
+		return fmt.Errorf("check replica set: %w", err)
+	}
+	if !isReplSet {
+		return fmt.Errorf("source must be a replica set to use Sync (change streams require an oplog)")
+	}
+
+	startAt, err := operationTime(ctx, sourceClient)
+	if err != nil {
+		return fmt.Errorf("read operation time: %w", err)
+	}
+
+	if err := i.Import(ctx, opts...); err != nil {
+		return fmt.Errorf("initial import: %w", err)
+	}
+
+	names, err := i.source.ListDatabases(ctx)
+	if err != nil {
+		return fmt.Errorf("list database names: %w", err)
+	}
+	names = cfg.filterDatabases(names...)
+	cfg.log(fmt.Sprintf("Watching databases for changes: %v", names))
+
+	group, ctx := errgroup.WithContext(ctx)
+	for _, name := range names {
+		name := name
+		group.Go(func() error {
+			if err := i.syncDatabase(ctx, cfg, sourceClient, targetClient, name, startAt); err != nil {
+				return fmt.Errorf("sync %q database: %w", name, err)
+			}
+			return nil
+		})
+	}
+
+	return group.Wait()
+}
+
+// liveSourceClient returns the *mongo.Client backing the Importer's source,
+// failing with a clear error if the source is not a live MongoDB deployment
+// (e.g. an archive file), since change streams require an oplog to tail.
+func (i *Importer) liveSourceClient() (*mongo.Client, error) {
+	c, ok := i.source.(interface{ Client() *mongo.Client })
+	if !ok {
+		return nil, fmt.Errorf("sync requires a live MongoDB source, not %T", i.source)
+	}
+	return c.Client(), nil
+}
+
+// liveTargetClient returns the *mongo.Client backing the Importer's target,
+// failing with a clear error if the target is not a live MongoDB deployment.
+func (i *Importer) liveTargetClient() (*mongo.Client, error) {
+	c, ok := i.target.(interface{ Client() *mongo.Client })
+	if !ok {
+		return nil, fmt.Errorf("sync requires a live MongoDB target, not %T", i.target)
+	}
+	return c.Client(), nil
+}
+
+// isReplicaSet reports whether the given client is connected to a replica
+// set, following the same "hello"/"isMaster" pattern used by the MongoDB
+// tools to probe deployment topology.
+func isReplicaSet(ctx context.Context, client *mongo.Client) (bool, error) {
+	var reply bson.M
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&reply); err != nil {
+		return false, fmt.Errorf("hello: %w", err)
+	}
+	_, ok := reply["setName"]
+	return ok, nil
+}
+
+// operationTime reads the client's current cluster operation time. Sync
+// reads it before the initial copy and uses it as the starting point of the
+// change streams it opens after the copy, so changes made during the copy
+// are not missed.
+func operationTime(ctx context.Context, client *mongo.Client) (primitive.Timestamp, error) {
+	var reply struct {
+		OperationTime primitive.Timestamp `bson:"operationTime"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "ping", Value: 1}}).Decode(&reply); err != nil {
+		return primitive.Timestamp{}, fmt.Errorf("ping: %w", err)
+	}
+	return reply.OperationTime, nil
+}
+
+// syncDatabase tails the change stream of a single source database and
+// applies every event to the corresponding target database, resuming from a
+// previously persisted resume token when one is available.
+func (i *Importer) syncDatabase(ctx context.Context, cfg importConfig, sourceClient, targetClient *mongo.Client, name string, startAt primitive.Timestamp) error {
+	cfg.log(fmt.Sprintf("[%s]: Watching for changes...", name))
+
+	db := sourceClient.Database(name)
+	target := targetClient.Database(name)
+
+	streamOpts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	token, err := i.loadResumeToken(ctx, target, name)
+	if err != nil {
+		return fmt.Errorf("load resume token: %w", err)
+	}
+	if token != nil {
+		cfg.log(fmt.Sprintf("[%s]: Resuming change stream after persisted token.", name))
+		streamOpts.SetResumeAfter(token)
+	} else {
+		streamOpts.SetStartAtOperationTime(&startAt)
+	}
+
+	stream, err := db.Watch(ctx, mongo.Pipeline{}, streamOpts)
+	if err != nil {
+		return fmt.Errorf("watch: %w", err)
+	}
+	defer stream.Close(ctx)
+
+	for stream.Next(ctx) {
+		var event bson.M
+		if err := stream.Decode(&event); err != nil {
+			return fmt.Errorf("decode change event: %w", err)
+		}
+
+		if err := i.applyChangeEvent(ctx, cfg, target, event); err != nil {
+			return fmt.Errorf("apply change event: %w", err)
+		}
+
+		if err := i.saveResumeToken(ctx, target, name, stream.ResumeToken()); err != nil {
+			return fmt.Errorf("save resume token: %w", err)
+		}
+	}
+
+	if err := stream.Err(); err != nil {
+		return fmt.Errorf("change stream: %w", err)
+	}
+
+	return ctx.Err()
+}
+
+// applyChangeEvent replays a single change stream event from the source
+// database against the equivalent collection on the target database.
+func (i *Importer) applyChangeEvent(ctx context.Context, cfg importConfig, target *mongo.Database, event bson.M) error {
+	opType, _ := event["operationType"].(string)
+
+	ns, _ := event["ns"].(bson.M)
+	coll, _ := ns["coll"].(string)
+
+	if opType != "dropDatabase" && len(cfg.filterCollections(target.Name(), coll)) == 0 {
+		cfg.log(fmt.Sprintf("[%s/%s]: skipping change event for excluded collection", target.Name(), coll))
+		return nil
+	}
+
+	switch opType {
+	case "insert":
+		docKey, _ := event["documentKey"].(bson.M)
+		doc, _ := event["fullDocument"].(bson.M)
+		cfg.log(fmt.Sprintf("[%s/%s]: insert", target.Name(), coll))
+		// ReplaceOne-with-upsert instead of InsertOne: applyChangeEvent can
+		// run again for the same event if the process dies after writing it
+		// but before saveResumeToken persists past it, and an InsertOne
+		// would fail the whole Sync on the resulting duplicate key.
+		opts := options.Replace().SetUpsert(true)
+		if _, err := target.Collection(coll).ReplaceOne(ctx, docKey, doc, opts); err != nil {
+			return fmt.Errorf("insert: %w", err)
+		}
+	case "update", "replace":
+		docKey, _ := event["documentKey"].(bson.M)
+		doc, ok := event["fullDocument"].(bson.M)
+		if !ok {
+			cfg.log(fmt.Sprintf("[%s/%s]: skipping update without full document (document removed since)", target.Name(), coll))
+			return nil
+		}
+		cfg.log(fmt.Sprintf("[%s/%s]: %s", target.Name(), coll, opType))
+		opts := options.Replace().SetUpsert(true)
+		if _, err := target.Collection(coll).ReplaceOne(ctx, docKey, doc, opts); err != nil {
+			return fmt.Errorf("replace: %w", err)
+		}
+	case "delete":
+		docKey, _ := event["documentKey"].(bson.M)
+		cfg.log(fmt.Sprintf("[%s/%s]: delete", target.Name(), coll))
+		if _, err := target.Collection(coll).DeleteOne(ctx, docKey); err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+	case "drop":
+		cfg.log(fmt.Sprintf("[%s/%s]: drop", target.Name(), coll))
+		if err := target.Collection(coll).Drop(ctx); err != nil {
+			return fmt.Errorf("drop collection: %w", err)
+		}
+	case "rename":
+		to, _ := event["to"].(bson.M)
+		toDB, _ := to["db"].(string)
+		toColl, _ := to["coll"].(string)
+		cfg.log(fmt.Sprintf("[%s/%s]: rename to %s.%s", target.Name(), coll, toDB, toColl))
+		cmd := bson.D{
+			{Key: "renameCollection", Value: fmt.Sprintf("%s.%s", target.Name(), coll)},
+			{Key: "to", Value: fmt.Sprintf("%s.%s", toDB, toColl)},
+			{Key: "dropTarget", Value: true},
+		}
+		if err := target.Client().Database("admin").RunCommand(ctx, cmd).Err(); err != nil {
+			return fmt.Errorf("rename collection: %w", err)
+		}
+	case "dropDatabase":
+		cfg.log(fmt.Sprintf("[%s]: drop database", target.Name()))
+		if err := target.Drop(ctx); err != nil {
+			return fmt.Errorf("drop database: %w", err)
+		}
+	default:
+		cfg.log(fmt.Sprintf("[%s/%s]: ignoring unsupported change event %q", target.Name(), coll, opType))
+	}
+
+	return nil
+}
+
+// loadResumeToken reads the persisted resume token for a database from the
+// target's mongomove_state collection, returning nil if none was saved yet.
+func (i *Importer) loadResumeToken(ctx context.Context, target *mongo.Database, name string) (bson.Raw, error) {
+	var state syncState
+	err := target.Collection(stateCollection).FindOne(ctx, bson.M{"_id": name}).Decode(&state)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state.ResumeToken, nil
+}
+
+// saveResumeToken persists the latest resume token for a database to the
+// target's mongomove_state collection, so a restarted Sync can resume
+// instead of starting over.
+func (i *Importer) saveResumeToken(ctx context.Context, target *mongo.Database, name string, token bson.Raw) error {
+	state := syncState{
+		ID:          name,
+		ResumeToken: token,
+		UpdatedAt:   time.Now(),
+	}
+	opts := options.Replace().SetUpsert(true)
+	_, err := target.Collection(stateCollection).ReplaceOne(ctx, bson.M{"_id": name}, state, opts)
+	return err
+}
@@ -0,0 +1,38 @@
+package mongomove
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TransformFunc is called for every document read from the source before it
+// is written to the target. It may rewrite the document, or drop it
+// entirely by returning ok=false.
+type TransformFunc func(ctx context.Context, db, coll string, doc bson.M) (out bson.M, ok bool, err error)
+
+// Transform appends a transform function to the chain that every document
+// passes through before being written to the target. Multiple Transform
+// options are applied in the order they were given; a document dropped by
+// one (ok=false) short-circuits the rest of the chain and is not written.
+func Transform(fn TransformFunc) ImportOption {
+	return func(cfg *importConfig) {
+		cfg.transforms = append(cfg.transforms, fn)
+	}
+}
+
+// AggregationPipeline makes importCollection read a namespace's documents
+// through an aggregation pipeline instead of a plain Find, letting callers
+// project or filter server-side for cheap partial copies. It is not
+// supported for namespaces read from an archive source. A resumed Import
+// reads such a namespace through the pipeline from the start again; its
+// checkpointed _id is not applied.
+func AggregationPipeline(db, coll string, pipeline mongo.Pipeline) ImportOption {
+	return func(cfg *importConfig) {
+		if cfg.aggPipelines == nil {
+			cfg.aggPipelines = make(map[string]mongo.Pipeline)
+		}
+		cfg.aggPipelines[db+"."+coll] = pipeline
+	}
+}